@@ -0,0 +1,142 @@
+package pat
+
+import "strings"
+
+// classifySegment reports whether seg is a literal segment. If not, prefix
+// is the literal text required before its ':' (empty for a bare splat).
+// catchAll reports whether seg is a final trailing "..." capture.
+func classifySegment(seg string) (literal bool, prefix string, catchAll bool) {
+	if strings.HasSuffix(seg, "...") {
+		catchAll = true
+		seg = strings.TrimSuffix(seg, "...")
+	}
+	if idx := strings.IndexByte(seg, ':'); idx >= 0 {
+		return false, seg[:idx], catchAll
+	}
+	return true, seg, catchAll
+}
+
+// specificityOverlap compares two path patterns (already stripped of any
+// host and method prefix) segment by segment. overlap reports whether some
+// request path could match both; when it does, ambiguous reports whether
+// neither pattern is unambiguously more specific than the other — a
+// literal beats a prefixed splat beats a bare splat, and a trailing-slash
+// subtree is less specific than any pattern that extends it.
+func specificityOverlap(pathA, pathB string) (overlap, ambiguous bool) {
+	segsA, trailA := splitSegments(pathA)
+	segsB, trailB := splitSegments(pathB)
+
+	minLen := len(segsA)
+	if len(segsB) < minLen {
+		minLen = len(segsB)
+	}
+
+	var aMoreSpecific, bMoreSpecific bool
+	for i := 0; i < minLen; i++ {
+		litA, prefixA, catchA := classifySegment(segsA[i])
+		litB, prefixB, catchB := classifySegment(segsB[i])
+
+		switch {
+		case litA && litB:
+			if prefixA != prefixB {
+				return false, false // disjoint literals
+			}
+		case litA && !litB:
+			if !strings.HasPrefix(prefixA, prefixB) {
+				return false, false // literal doesn't satisfy the splat's prefix
+			}
+			aMoreSpecific = true
+		case litB && !litA:
+			if !strings.HasPrefix(prefixB, prefixA) {
+				return false, false
+			}
+			bMoreSpecific = true
+		default: // both splats
+			switch {
+			case prefixA == prefixB:
+				// same requirement, tie at this position
+			case strings.HasPrefix(prefixA, prefixB):
+				aMoreSpecific = true // A's prefix is the stricter one
+			case strings.HasPrefix(prefixB, prefixA):
+				bMoreSpecific = true
+			default:
+				return false, false // disjoint prefixes
+			}
+		}
+
+		if catchA != catchB {
+			// A catch-all reaches exactly as deep as a sibling pattern ending
+			// here, so the two do overlap, but lookup always tries
+			// findExact before findCatchAll — same deterministic priority as
+			// a literal over a subtree below — so whichever side isn't the
+			// catch-all is unambiguously more specific.
+			if catchA {
+				bMoreSpecific = true
+			} else {
+				aMoreSpecific = true
+			}
+		}
+
+		if catchA || catchB {
+			// A catch-all only constrains the one segment it starts on;
+			// whatever follows is unconstrained, so there's nothing left
+			// to compare.
+			break
+		}
+	}
+
+	switch {
+	case len(segsA) == len(segsB):
+		if trailA != trailB {
+			// Same segments, but one requires an exact match and the other
+			// a trailing slash: they never match the same concrete path.
+			return false, false
+		}
+		return true, aMoreSpecific == bMoreSpecific
+
+	case len(segsA) < len(segsB):
+		shortIsOpenEnded := trailA || (len(segsA) > 0 && strings.HasSuffix(segsA[len(segsA)-1], "..."))
+		if !shortIsOpenEnded {
+			return false, false // A is rooted at an exact depth B goes past
+		}
+		return true, false // B is unambiguously more specific
+
+	default:
+		shortIsOpenEnded := trailB || (len(segsB) > 0 && strings.HasSuffix(segsB[len(segsB)-1], "..."))
+		if !shortIsOpenEnded {
+			return false, false
+		}
+		return true, false // A is unambiguously more specific
+	}
+}
+
+// patternsConflict reports whether pathA and pathB (host- and
+// method-stripped path patterns) can match the same request path with no
+// unambiguous winner between them.
+func patternsConflict(pathA, pathB string) bool {
+	overlap, ambiguous := specificityOverlap(pathA, pathB)
+	return overlap && ambiguous
+}
+
+// checkConflicts panics if pattern (registered for method, with path
+// already split off) is ambiguous with respect to any existing explicit
+// registration for a method that could share a request with it.
+func (mux *ServeMux) checkConflicts(pattern, method, path string) {
+	host, pathOnly := splitHostPattern(path)
+
+	for existingPattern, existing := range mux.m {
+		if !existing.explicit {
+			continue // bookkeeping entry (e.g. an implicit /tree redirect)
+		}
+		if method != "" && existing.method != "" && method != existing.method {
+			continue // disjoint methods can never share a request
+		}
+		existingHost, existingPathOnly := splitHostPattern(existing.pattern)
+		if host != existingHost {
+			continue // different routing trees entirely, never ambiguous
+		}
+		if patternsConflict(pathOnly, existingPathOnly) {
+			panic("pat: pattern " + pattern + " conflicts with existing pattern " + existingPattern)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package pat
+
+import (
+	"net/http"
+	"testing"
+)
+
+func panics(f func()) (recovered bool) {
+	defer func() {
+		if recover() != nil {
+			recovered = true
+		}
+	}()
+	f()
+	return false
+}
+
+func TestHandleConflictingPatternsPanic(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+	}{
+		{"two bare splats", "/users/:id", "/users/:name"},
+		{"two prefixed splats with the same prefix", "/users/user:id", "/users/user:name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := NewServeMux()
+			mux.HandleFunc(tt.a, func(w http.ResponseWriter, r *http.Request) {})
+			if !panics(func() { mux.HandleFunc(tt.b, func(w http.ResponseWriter, r *http.Request) {}) }) {
+				t.Errorf("Handle(%q) after Handle(%q) did not panic", tt.b, tt.a)
+			}
+		})
+	}
+}
+
+func TestHandleNonConflictingPatternsOK(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+	}{
+		{"literal more specific than sibling bare splat", "/users/:id", "/users/admin"},
+		{"prefixed splat more specific than sibling bare splat", "/users/:id", "/users/user:id"},
+		{"exact patterns of different depths never overlap", "/users/:id", "/users/:id/profile"},
+		{"subtree less specific than deeper literal", "/images/", "/images/thumbnails/"},
+		{"different methods never overlap", "GET /users/:id", "POST /users/:id"},
+		{"host-specific vs generic never overlap", "/codesearch", "codesearch.google.com/"},
+		{"exact vs its own subtree don't overlap", "/a", "/a/"},
+		{"exact splat more specific than sibling catch-all", "/files/:id", "/files/:path..."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := NewServeMux()
+			mux.HandleFunc(tt.a, func(w http.ResponseWriter, r *http.Request) {})
+			if panics(func() { mux.HandleFunc(tt.b, func(w http.ResponseWriter, r *http.Request) {}) }) {
+				t.Errorf("Handle(%q) after Handle(%q) panicked unexpectedly", tt.b, tt.a)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package pat
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Middleware wraps an http.Handler to produce another, e.g. to log
+// requests or enforce auth before calling through to the next handler.
+type Middleware func(http.Handler) http.Handler
+
+// Use registers middleware that wraps every request served by mux,
+// including the generated 404/405 handlers. Requests that ServeHTTP
+// redirects to a cleaned path are answered without it. Middleware added
+// this way runs before any Group's middleware, in the order Use was called.
+func (mux *ServeMux) Use(mw ...Middleware) {
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+	mux.middlewares = append(mux.middlewares, mw...)
+}
+
+// Group returns a Group that registers its routes against mux with prefix
+// prepended to every pattern and middlewares wrapped around every handler.
+// A Group resolves prefix and middleware at the time each route is
+// registered, not at request time, so it's safe to create a Group before or
+// after calling Handle directly on mux.
+func (mux *ServeMux) Group(prefix string, middlewares ...Middleware) *Group {
+	return &Group{mux: mux, prefix: prefix, middlewares: append([]Middleware(nil), middlewares...)}
+}
+
+// Group is a subrouter: a path prefix and a middleware chain shared by a
+// set of routes, all ultimately registered against the same ServeMux.
+type Group struct {
+	mux         *ServeMux
+	prefix      string
+	middlewares []Middleware
+}
+
+// joinPrefix concatenates a group prefix with a route pattern's path,
+// collapsing the slash between them so "/api/" + "/v1" and "/api" + "/v1"
+// both produce "/api/v1".
+func joinPrefix(prefix, path string) string {
+	if prefix == "" {
+		return path
+	}
+	if strings.HasSuffix(prefix, "/") {
+		return prefix + strings.TrimPrefix(path, "/")
+	}
+	return prefix + path
+}
+
+// wrapMiddleware applies mws around h, with mws[0] ending up outermost so
+// middleware runs in the order it was registered.
+func wrapMiddleware(h http.Handler, mws []Middleware) http.Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	return h
+}
+
+// Handle registers handler for pattern, with the group's prefix prepended
+// to its path and the group's middleware wrapped around it, against the
+// group's parent mux.
+func (g *Group) Handle(pattern string, handler http.Handler) {
+	method, path := splitMethod(pattern)
+	full := joinPrefix(g.prefix, path)
+	if method != "" {
+		full = method + " " + full
+	}
+	g.mux.Handle(full, wrapMiddleware(handler, g.middlewares))
+}
+
+// HandleFunc registers the handler function for pattern, as Handle does.
+func (g *Group) HandleFunc(pattern string, handler http.HandlerFunc) {
+	g.Handle(pattern, handler)
+}
+
+// Use adds middlewares to the group's chain, wrapping handlers registered
+// by this Group (and by sub-Groups created from it afterwards) from this
+// point on. It doesn't affect routes already registered, nor sub-Groups
+// created before the call, which keep the chain they copied.
+func (g *Group) Use(mw ...Middleware) {
+	g.middlewares = append(g.middlewares, mw...)
+}
+
+// Group returns a sub-Group whose prefix extends this Group's prefix and
+// whose middleware chain extends this Group's, in the same way Group does
+// on a ServeMux.
+func (g *Group) Group(prefix string, middlewares ...Middleware) *Group {
+	chain := append(append([]Middleware(nil), g.middlewares...), middlewares...)
+	return &Group{mux: g.mux, prefix: joinPrefix(g.prefix, prefix), middlewares: chain}
+}
@@ -0,0 +1,99 @@
+package pat
+
+import (
+	"net/http"
+	"testing"
+)
+
+func withHeader(name, value string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add(name, value)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func TestGroupPrefix(t *testing.T) {
+	mux := NewServeMux()
+	api := mux.Group("/api")
+	api.HandleFunc("/:id", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(Param(r, "id"))) })
+
+	v1 := mux.Group("/v1/")
+	v1.HandleFunc("/ping", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("pong")) })
+
+	if got := serve(mux, "GET", "/api/42").Body.String(); got != "42" {
+		t.Errorf("body = %q, want %q", got, "42")
+	}
+	if got := serve(mux, "GET", "/v1/ping").Body.String(); got != "pong" {
+		t.Errorf("body = %q, want %q", got, "pong")
+	}
+}
+
+func TestGroupMiddlewareOrder(t *testing.T) {
+	mux := NewServeMux()
+	mux.Use(withHeader("X-Order", "mux"))
+
+	g := mux.Group("/admin", withHeader("X-Order", "group"))
+	g.HandleFunc("/dash", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("X-Order", "handler")
+	})
+
+	w := serve(mux, "GET", "/admin/dash")
+	got := w.Header()["X-Order"]
+	want := []string{"mux", "group", "handler"}
+	if len(got) != len(want) {
+		t.Fatalf("X-Order = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("X-Order[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestGroupSafeBeforeOrAfterHandle(t *testing.T) {
+	mux := NewServeMux()
+	mux.HandleFunc("/before", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("before")) })
+
+	g := mux.Group("/g")
+	g.HandleFunc("/early", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("early")) })
+
+	mux.HandleFunc("/after", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("after")) })
+
+	g.HandleFunc("/late", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("late")) })
+
+	if got := serve(mux, "GET", "/g/early").Body.String(); got != "early" {
+		t.Errorf("body = %q, want %q", got, "early")
+	}
+	if got := serve(mux, "GET", "/g/late").Body.String(); got != "late" {
+		t.Errorf("body = %q, want %q", got, "late")
+	}
+}
+
+func TestSubGroupInheritsPrefixAndMiddleware(t *testing.T) {
+	mux := NewServeMux()
+	api := mux.Group("/api", withHeader("X-Order", "api"))
+	v2 := api.Group("/v2", withHeader("X-Order", "v2"))
+	v2.HandleFunc("/users", func(w http.ResponseWriter, r *http.Request) {})
+
+	w := serve(mux, "GET", "/api/v2/users")
+	got := w.Header()["X-Order"]
+	want := []string{"api", "v2"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("X-Order = %v, want %v", got, want)
+	}
+}
+
+func TestGroupMethodPrefix(t *testing.T) {
+	mux := NewServeMux()
+	g := mux.Group("/api")
+	g.HandleFunc("POST /widgets", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("created")) })
+
+	if got := serve(mux, "POST", "/api/widgets").Body.String(); got != "created" {
+		t.Errorf("body = %q, want %q", got, "created")
+	}
+	if w := serve(mux, "GET", "/api/widgets"); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("got status %d, want %d", w.Code, http.StatusMethodNotAllowed)
+	}
+}
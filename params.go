@@ -0,0 +1,89 @@
+package pat
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type contextKey int
+
+const paramsContextKey contextKey = 0
+
+// params holds the path parameters captured from the pattern that matched a
+// request, keyed by name with the splat's leading colon stripped.
+type params map[string]string
+
+// Param returns the value of the named path parameter captured for r by the
+// pattern that matched it (e.g. Param(r, "id") for a route registered as
+// "/users/:id"), or "" if it wasn't captured.
+func Param(r *http.Request, name string) string {
+	return Params(r)[name]
+}
+
+// Params returns all path parameters captured for r by the pattern that
+// matched it. It returns a non-nil, possibly empty map.
+func Params(r *http.Request) map[string]string {
+	if p, ok := r.Context().Value(paramsContextKey).(params); ok {
+		return p
+	}
+	return params{}
+}
+
+// parseParams extracts the path parameters pattern captures from path. It
+// returns nil if pattern has no splats.
+func parseParams(pattern, path string) params {
+	if !strings.Contains(pattern, ":") {
+		return nil
+	}
+
+	_pattern := strings.Split(pattern, "/")
+	_path := strings.Split(path, "/")
+	last := len(_pattern) - 1
+
+	p := make(params)
+
+	// Traverse each path component
+	for i, item := range _pattern {
+		// Determine where's the splat, if there's one
+		switch index := strings.Index(item, ":"); index {
+		case -1:
+			// No splat found
+		case 0:
+			// Splat found
+			name := item[1:]
+			if i == last && strings.HasSuffix(name, "...") {
+				// Trailing greedy capture: joins every remaining segment.
+				p[strings.TrimSuffix(name, "...")] = strings.Join(_path[i:], "/")
+			} else {
+				p[name] = _path[i]
+			}
+		default:
+			// Prefixed splat
+			name := item[index+1:]
+			if i == last && strings.HasSuffix(name, "...") {
+				p[strings.TrimSuffix(name, "...")] = strings.Join(_path[i:], "/")
+			} else {
+				p[name] = _path[i][index:]
+			}
+		}
+	}
+
+	return p
+}
+
+// parseSplats is the legacy counterpart of parseParams, used only when
+// ServeMux.LegacyQueryParams is set. It re-adds the leading colon parseParams
+// strips, matching the query-string keys pat used before Param/Params
+// existed.
+func parseSplats(pattern, path string) url.Values {
+	p := parseParams(pattern, path)
+	if p == nil {
+		return nil
+	}
+	values := make(url.Values, len(p))
+	for k, v := range p {
+		values.Add(":"+k, v)
+	}
+	return values
+}
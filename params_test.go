@@ -0,0 +1,58 @@
+package pat
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestParamsContext(t *testing.T) {
+	mux := NewServeMux()
+	mux.HandleFunc("/hello/:name", func(w http.ResponseWriter, r *http.Request) {
+		if got := Param(r, "name"); got != "world" {
+			t.Errorf("Param(r, %q) = %q, want %q", "name", got, "world")
+		}
+		if got := len(Params(r)); got != 1 {
+			t.Errorf("len(Params(r)) = %d, want %d", got, 1)
+		}
+		if r.URL.RawQuery != "" {
+			t.Errorf("RawQuery = %q, want empty (LegacyQueryParams unset)", r.URL.RawQuery)
+		}
+	})
+
+	r, _ := http.NewRequest("GET", "/hello/world", nil)
+	mux.ServeHTTP(httptest.NewRecorder(), r)
+}
+
+func TestParamsDoesNotCollideWithQueryString(t *testing.T) {
+	mux := NewServeMux()
+	mux.HandleFunc("/:id", func(w http.ResponseWriter, r *http.Request) {
+		if got := Param(r, "id"); got != "42" {
+			t.Errorf("Param(r, %q) = %q, want %q", "id", got, "42")
+		}
+		if got := r.URL.Query().Get("id"); got != "real" {
+			t.Errorf("query param %q = %q, want %q (should be untouched)", "id", got, "real")
+		}
+	})
+
+	r, _ := http.NewRequest("GET", "/42?id=real", nil)
+	mux.ServeHTTP(httptest.NewRecorder(), r)
+}
+
+func TestParamsLegacyQueryParams(t *testing.T) {
+	mux := NewServeMux()
+	mux.LegacyQueryParams = true
+	mux.HandleFunc("/:id", func(w http.ResponseWriter, r *http.Request) {
+		values, err := url.ParseQuery(r.URL.RawQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := values.Get(":id"); got != "42" {
+			t.Errorf(":id in RawQuery = %q, want %q", got, "42")
+		}
+	})
+
+	r, _ := http.NewRequest("GET", "/42", nil)
+	mux.ServeHTTP(httptest.NewRecorder(), r)
+}
@@ -3,128 +3,36 @@
 package pat
 
 import (
+	"context"
 	"net/http"
-	"net/url"
 	"path"
 	"strings"
 	"sync"
 )
 
-// Does path match pattern?
-func pathMatch(pattern, path string) bool {
-	// Empty pattern matches nothing
-	if len(pattern) == 0 {
-		return false
-	}
-
-	if strings.Contains(pattern, ":") {
-		return pathMatchSplat(pattern, path)
-	}
-
-	return pathMatchFlat(pattern, path)
+// methods holds the standard HTTP verbs recognized as a method prefix on a
+// pattern passed to Handle/HandleFunc, e.g. "GET /users/:id".
+var methods = map[string]bool{
+	"GET":     true,
+	"HEAD":    true,
+	"POST":    true,
+	"PUT":     true,
+	"PATCH":   true,
+	"DELETE":  true,
+	"CONNECT": true,
+	"OPTIONS": true,
+	"TRACE":   true,
 }
 
-// pathMatchFlat matches exact patterns. `path` may contain a (sub)domain.
-// '/a' matches: '/a' and '/a/'
-// '/a/' matches: '/a', '/a/' and '/a/whatever'
-func pathMatchFlat(pattern, path string) bool {
-	n := len(pattern)
-	if pattern[n-1] != '/' {
-		return pattern == path
+// splitMethod splits a leading "METHOD " token off pattern, returning the
+// method and the remaining path pattern. If pattern has no such prefix,
+// method is "" and rest is pattern unchanged, meaning the pattern matches
+// requests of any method.
+func splitMethod(pattern string) (method, rest string) {
+	if i := strings.IndexByte(pattern, ' '); i >= 0 && methods[pattern[:i]] {
+		return pattern[:i], pattern[i+1:]
 	}
-	return len(path) >= n && path[:n] == pattern
-}
-
-// pathMatchSplat matches patterns with capture groups. `path` may contain a
-// (sub)domain.
-// '/:a' will match: '/hello' and '/hello/'
-// '/:a/' will match: '/hello', '/hello/' and '/hello/whatever'
-func pathMatchSplat(pattern, path string) bool {
-	var leadingSlash bool
-
-	_pattern := strings.Split(pattern, "/")
-	// Number of slashes in pattern
-	slashes := strings.Count(pattern, "/")
-
-	// Patterns with a leading slash can match paths with `n-1` or more slashes,
-	// `n` being the total number of slashes of `pattern`.
-	if pattern[len(pattern)-1] == '/' {
-		leadingSlash = true
-
-		slashes -= 1
-		// Last item of _pattern will be empty causing everything to NOT match.
-		_pattern = _pattern[:slashes]
-	}
-
-	// Split path by slashes
-	_path := strings.Split(path, "/")
-
-	switch leadingSlash {
-	case true:
-		// Check (n-1)+ slashes on path
-		if len(_path) <= slashes {
-			return false
-		}
-	case false:
-		// There should be the same number of slashes on pattern and path
-		if !leadingSlash && strings.Count(path, "/") != slashes {
-			return false
-		}
-	}
-
-	// Traverse each path component
-	for i, item := range _pattern {
-		// Split by splat mark
-		index := strings.Index(item, ":")
-
-		// Determine where's the splat, if there's one
-		switch index {
-		// No splat found
-		case -1:
-			if item != _path[i] {
-				return false
-			}
-		// Splat found
-		case 0:
-			// Splat will match whatever its in _path[i]
-		// Prefixed splat
-		default:
-			prefix := item[:index]
-			if !strings.HasPrefix(_path[i], prefix) {
-				return false
-			}
-		}
-	}
-
-	return true
-}
-
-func parseSplats(pattern, path string) url.Values {
-	_pattern := strings.Split(pattern, "/")
-	_path := strings.Split(path, "/")
-
-	if !strings.Contains(pattern, ":") {
-		return nil
-	}
-
-	values := make(url.Values)
-
-	// Traverse each path component
-	for i, item := range _pattern {
-		// Determine where's the splat, if there's one
-		switch index := strings.Index(item, ":"); index {
-		case -1:
-		// No splat found
-		case 0:
-			// Splat found
-			values.Add(item, _path[i])
-		default:
-			// Prefixed splat
-			values.Add(item[index:], _path[i][index:])
-		}
-	}
-
-	return values
+	return "", pattern
 }
 
 // ServeMux is an HTTP request multiplexer.
@@ -147,17 +55,35 @@ func parseSplats(pattern, path string) url.Values {
 // "/codesearch" and "codesearch.google.com/" without also taking over
 // requests for "http://www.google.com/".
 //
+// A path segment of the form ":name" is a splat, capturing that segment of
+// the request path for retrieval with Param/Params; it may be prefixed with
+// literal text, e.g. "user:id" matches "user42" and captures "42". A final
+// segment of the form ":name..." is a catch-all, capturing every remaining
+// segment of the request path, slashes included.
+//
 // ServeMux also takes care of sanitizing the URL request path,
 // redirecting any request containing . or .. elements to an
 // equivalent .- and ..-free URL.
 type ServeMux struct {
 	mu sync.RWMutex
-	m  map[string]muxEntry
+	m  map[string]muxEntry // registration bookkeeping, keyed by the pattern as passed to Handle
+	t  muxTrie             // routing index used to dispatch requests
+
+	// LegacyQueryParams, if set, additionally encodes captured path
+	// parameters into the request's URL.RawQuery, as pat did before path
+	// parameters were exposed via Param/Params. This mutates r.URL and can
+	// collide with a real query parameter of the same name; prefer
+	// Param/Params and leave this unset in new code.
+	LegacyQueryParams bool
+
+	middlewares []Middleware // registered via Use, applied to every request
 }
 
 type muxEntry struct {
 	explicit bool
 	h        http.Handler
+	pattern  string // path pattern, with any method prefix stripped
+	method   string // HTTP method this entry is restricted to, or "" for any
 }
 
 // NewServeMux allocates and returns a new ServeMux.
@@ -180,42 +106,68 @@ func cleanPath(p string) string {
 	return np
 }
 
-// Find a handler on a handler map given a path string
-// Most-specific (longest) pattern wins
-func (mux *ServeMux) match(path string) (pattern string, h http.Handler) {
-	n := 0
-	for k, v := range mux.m {
-		if !pathMatch(k, path) {
-			continue
+// match looks up the handler registered for method and path, trying the
+// host-specific trie root for host first and falling back to the generic
+// root. found reports whether a handler was located; pathMatched reports
+// whether path matched some registered pattern even if method didn't, which
+// handler uses to choose between 404 and 405.
+func (mux *ServeMux) match(method, host, path string) (entry muxEntry, found, pathMatched bool) {
+	if root, ok := mux.t.hosts[host]; ok {
+		if entry, found, pathMatched = lookup(root, method, path); found {
+			return
 		}
-		if h == nil || len(k) > n {
-			n = len(k)
-			pattern = k
-			h = v.h
+	}
+	entry, found, genericMatched := lookup(&mux.t.root, method, path)
+	return entry, found, pathMatched || genericMatched
+}
+
+// allowedMethods returns the sorted, deduplicated list of methods explicitly
+// registered for patterns matching host+path, for use in a 405 response. It
+// returns nil if no method-restricted pattern matches.
+func (mux *ServeMux) allowedMethods(host, path string) []string {
+	if root, ok := mux.t.hosts[host]; ok {
+		if methods := allowed(root, path); methods != nil {
+			return methods
 		}
 	}
-	return
+	return allowed(&mux.t.root, path)
+}
+
+// methodNotAllowedHandler responds 405 Method Not Allowed, listing allowed
+// in the Allow header.
+func methodNotAllowedHandler(allowed []string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		http.Error(w, "405 method not allowed", http.StatusMethodNotAllowed)
+	})
 }
 
-// handler returns the handler to use for the request r.
-func (mux *ServeMux) handler(r *http.Request) http.Handler {
+// handler returns the handler to use for the request r, along with the
+// request to serve it with (carrying captured path parameters in its
+// context, and possibly a copy of r with LegacyQueryParams applied).
+func (mux *ServeMux) handler(r *http.Request) (http.Handler, *http.Request) {
 	mux.mu.RLock()
 	defer mux.mu.RUnlock()
 
-	// Host-specific pattern takes precedence over generic ones
-	pattern, h := mux.match(r.Host + r.URL.Path)
-	if h == nil {
-		pattern, h = mux.match(r.URL.Path)
+	entry, found, pathMatched := mux.match(r.Method, r.Host, r.URL.Path)
+	if !found {
+		if pathMatched {
+			if allowed := mux.allowedMethods(r.Host, r.URL.Path); allowed != nil {
+				return methodNotAllowedHandler(allowed), r
+			}
+		}
+		return http.NotFoundHandler(), r
 	}
-	if h == nil {
-		h = http.NotFoundHandler()
-	} else {
-		params := parseSplats(pattern, r.URL.Path)
-		if params != nil {
-			r.URL.RawQuery = url.Values(params).Encode() + "&" + r.URL.RawQuery
+
+	if p := parseParams(entry.pattern, r.URL.Path); p != nil {
+		r = r.WithContext(context.WithValue(r.Context(), paramsContextKey, p))
+		if mux.LegacyQueryParams {
+			if values := parseSplats(entry.pattern, r.URL.Path); values != nil {
+				r.URL.RawQuery = values.Encode() + "&" + r.URL.RawQuery
+			}
 		}
 	}
-	return h
+	return entry.h, r
 }
 
 // ServeHTTP dispatches the request to the handler whose
@@ -229,11 +181,29 @@ func (mux *ServeMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
-	mux.handler(r).ServeHTTP(w, r)
+	h, r := mux.handler(r)
+	mux.mu.RLock()
+	mws := mux.middlewares
+	mux.mu.RUnlock()
+	wrapMiddleware(h, mws).ServeHTTP(w, r)
 }
 
 // Handle registers the handler for the given pattern.
-// If a handler already exists for pattern, Handle panics.
+// If a handler already exists for pattern, Handle panics. Handle also
+// panics if pattern is ambiguous with an existing registration that could
+// match the same request path with no unambiguous winner — e.g.
+// "/users/:id" and "/users/:name" both just as specific, so which one a
+// request for "/users/admin" should reach would depend on map iteration
+// order. A literal segment beats a prefixed splat beats a bare splat, and a
+// trailing-slash subtree is always less specific than a pattern extending
+// it, so those combinations register fine.
+//
+// pattern may optionally begin with an HTTP method and a single space, e.g.
+// "GET /users/:id" or "POST /users/", restricting the handler to that
+// method. A request whose path matches a registered pattern but whose
+// method doesn't match any variant of it is answered with 405 Method Not
+// Allowed and an Allow header listing the registered methods. A pattern
+// with no method prefix matches requests of any method, as before.
 func (mux *ServeMux) Handle(pattern string, handler http.Handler) {
 	mux.mu.Lock()
 	defer mux.mu.Unlock()
@@ -248,19 +218,41 @@ func (mux *ServeMux) Handle(pattern string, handler http.Handler) {
 		panic("http: multiple registrations for " + pattern)
 	}
 
-	mux.m[pattern] = muxEntry{
+	method, path := splitMethod(pattern)
+	if path == "" {
+		panic("http: invalid pattern " + pattern)
+	}
+	validateCatchAll(path)
+	mux.checkConflicts(pattern, method, path)
+
+	entry := muxEntry{
 		explicit: true,
 		h:        handler,
+		pattern:  path,
+		method:   method,
 	}
+	mux.m[pattern] = entry
+	mux.t.insert(path, method, entry)
 
 	// Helpful behavior:
 	// If pattern is /tree/, insert an implicit permanent redirect for /tree.
-	// It can be overridden by an explicit registration.
-	n := len(pattern)
-	if n > 0 && pattern[n-1] == '/' && !mux.m[pattern[0:n-1]].explicit {
-		mux.m[pattern[0:n-1]] = muxEntry{
-			h: http.RedirectHandler(pattern, http.StatusMovedPermanently),
+	// It can be overridden by an explicit registration. Patterns with a
+	// wildcard in any segment (e.g. "/hello/:a/" or "/users/:id/posts/") are
+	// skipped: there's no single literal URL to redirect to, and the trie
+	// already serves a request one segment short directly as a subtree match
+	// (see findSubtree).
+	n := len(path)
+	_, redirectPathOnly := splitHostPattern(path[:n-1])
+	if n > 0 && path[n-1] == '/' && !strings.ContainsRune(redirectPathOnly, ':') && !mux.m[pattern[0:len(pattern)-1]].explicit {
+		redirectKey := pattern[0 : len(pattern)-1]
+		redirectPath := path[0 : n-1]
+		redirectEntry := muxEntry{
+			h:       http.RedirectHandler(path, http.StatusMovedPermanently),
+			pattern: redirectPath,
+			method:  method,
 		}
+		mux.m[redirectKey] = redirectEntry
+		mux.t.insert(redirectPath, method, redirectEntry)
 	}
 }
 
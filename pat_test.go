@@ -1,41 +1,51 @@
 package pat
 
-import "testing"
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
 
-type PathTable struct {
-	pattern, path string
-	expected      bool
+func TestSplitMethod(t *testing.T) {
+	tests := []struct {
+		pattern, method, rest string
+	}{
+		{"/hello", "", "/hello"},
+		{"GET /hello", "GET", "/hello"},
+		{"POST /hello/:id", "POST", "/hello/:id"},
+		{"Get /hello", "", "Get /hello"}, // not all-uppercase, not a method
+		{"GETHER /hello", "", "GETHER /hello"},
+	}
+	for i, item := range tests {
+		method, rest := splitMethod(item.pattern)
+		if method != item.method || rest != item.rest {
+			t.Errorf("%d. splitMethod(%q) => (%q, %q), want (%q, %q)", i, item.pattern, method, rest, item.method, item.rest)
+		}
+	}
 }
 
-// (*) = Both patterns get auto-registered '/hello' and '/hello/' but the one
-// with a leading slash doesn't match.
-
-var pathTests = []PathTable{
-	{"/", "", false},                              // 1
-	{"/", "/", true},                              // 2
-	{"/", "/hello", true},                         // 3
-	{"/hello/", "/hello", false},                  // 4 (*)
-	{"/hello/", "/helloo", false},                 // 5
-	{"/hello/", "/hello/", true},                  // 6
-	{"/hello/", "/hello/whatever", true},          // 7
-	{"/:a", "/hello", true},                       // 8
-	{"/:a", "/hello/", false},                     // 9
-	{"/:a", "/world", true},                       // 10
-	{"/:a", "/hello/world", false},                // 11
-	{"/:a/", "/hello/world", true},                // 12
-	{"/:a/", "/hello/world/world", true},          // 13
-	{"/hello/:a", "/hello", false},                // 14
-	{"/hello/:a", "/hello/", true},                // 15
-	{"/hello/:a", "/helloo/", false},              // 16
-	{"/hello/:a", "/hello/world", true},           // 17
-	{"/hello/:a/", "/hello/world/whatever", true}, // 18
+func TestHandleMethodOnlyPatternPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Handle did not panic for a pattern with a method but no path")
+		}
+	}()
+	NewServeMux().HandleFunc("GET ", func(w http.ResponseWriter, r *http.Request) {})
 }
 
-func TestMatchPath(t *testing.T) {
-	for i, item := range pathTests {
-		output := pathMatch(item.pattern, item.path)
-		if output != item.expected {
-			t.Errorf("%d. match(%q, %q) => %v, want %v", i, item.pattern, item.path, output, item.expected)
-		}
+func TestMethodNotAllowed(t *testing.T) {
+	mux := NewServeMux()
+	mux.HandleFunc("GET /users/:id", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("POST /users/:id", func(w http.ResponseWriter, r *http.Request) {})
+
+	r, _ := http.NewRequest("DELETE", "/users/42", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("got status %d, want %d", w.Code, http.StatusMethodNotAllowed)
+	}
+	if allow := w.Header().Get("Allow"); allow != "GET, POST" {
+		t.Errorf("Allow header = %q, want %q", allow, "GET, POST")
 	}
 }
@@ -0,0 +1,365 @@
+package pat
+
+import (
+	"sort"
+	"strings"
+)
+
+// trieNode is one path segment in the routing trie. A node may carry a
+// literal child per distinct segment text, a wildcard child per distinct
+// literal prefix required before ':' (e.g. "user:id" and "admin:id" are
+// distinct wildcard children of the same node, "user:a" and "user:b" are
+// not — conflict detection rejects the second as ambiguous), at most one
+// catch-all child (a trailing "..." segment, e.g. ":path..."), an
+// exact-match handler per method (registered without a trailing slash) and a
+// subtree handler per method (registered with a trailing slash, matching
+// this node and everything beneath it).
+type trieNode struct {
+	children  map[string]*trieNode
+	wildcards []wildcardChild
+
+	catchAll       *trieNode // terminal node for a trailing ":name..." capture
+	catchAllPrefix string    // literal text required before ':' in the catch-all segment
+
+	handlers map[string]muxEntry // exact match at this depth, keyed by method ("" = any)
+	subtree  map[string]muxEntry // rooted-subtree match, keyed by method ("" = any)
+}
+
+// wildcardChild is one of a node's wildcard children, keyed by the literal
+// text required before ':' in its segment (e.g. "user" for "user:id", ""
+// for a bare ":id").
+type wildcardChild struct {
+	prefix string
+	node   *trieNode
+}
+
+// muxTrie indexes registered patterns by path segment for O(depth) lookup.
+// Host-qualified patterns live under a dedicated root per host; root serves
+// patterns with no host prefix.
+type muxTrie struct {
+	root  trieNode
+	hosts map[string]*trieNode
+}
+
+// rootFor returns the trie root that patterns for host should be inserted
+// into or looked up from, creating a host-specific root on first use.
+func (t *muxTrie) rootFor(host string) *trieNode {
+	if host == "" {
+		return &t.root
+	}
+	if t.hosts == nil {
+		t.hosts = make(map[string]*trieNode)
+	}
+	root, ok := t.hosts[host]
+	if !ok {
+		root = &trieNode{}
+		t.hosts[host] = root
+	}
+	return root
+}
+
+// splitHostPattern separates a leading host name off pattern. Patterns
+// starting with '/' have no host.
+func splitHostPattern(pattern string) (host, path string) {
+	if pattern == "" || pattern[0] == '/' {
+		return "", pattern
+	}
+	if i := strings.IndexByte(pattern, '/'); i >= 0 {
+		return pattern[:i], pattern[i:]
+	}
+	// No '/' at all: not a usable host+path pattern. Fall back to treating
+	// it as a literal (and, in practice, unreachable) path pattern, same as
+	// the pre-trie implementation did for this edge case.
+	return "", pattern
+}
+
+// splitSegments splits path on '/' into its non-empty segments, reporting
+// whether path ends in a trailing slash (a rooted-subtree pattern or
+// request).
+func splitSegments(path string) (segs []string, trailingSlash bool) {
+	trimmed := strings.TrimPrefix(path, "/")
+	if trimmed == "" {
+		return nil, true
+	}
+	if strings.HasSuffix(trimmed, "/") {
+		trailingSlash = true
+		trimmed = trimmed[:len(trimmed)-1]
+	}
+	if trimmed == "" {
+		return nil, trailingSlash
+	}
+	return strings.Split(trimmed, "/"), trailingSlash
+}
+
+// lastSegmentIsWildcard reports whether path's final segment (ignoring any
+// trailing slash) is a splat, e.g. "/hello/:a" and "/hello/:a/" both report
+// true for the "hello"/":a" split. There's no single literal URL such a
+// pattern could redirect to, unlike a purely literal subtree pattern.
+func lastSegmentIsWildcard(path string) bool {
+	segs, _ := splitSegments(path)
+	if len(segs) == 0 {
+		return false
+	}
+	return strings.ContainsRune(segs[len(segs)-1], ':')
+}
+
+// validateCatchAll panics if path uses a trailing "..." capture anywhere
+// but its final segment, e.g. "/files/:path.../more".
+func validateCatchAll(path string) {
+	segs := strings.Split(path, "/")
+	last := len(segs) - 1
+	for i, seg := range segs {
+		if i != last && strings.Contains(seg, "...") {
+			panic(`pat: "..." is only allowed in the final path segment: ` + path)
+		}
+	}
+}
+
+// child returns node's child for seg, creating a literal or wildcard child
+// as appropriate. A node may have several wildcard children, one per
+// distinct literal prefix (e.g. "user:id" and "admin:id" each get their own),
+// found or created by prefix text rather than reusing whichever wildcard
+// child happened to be created first.
+func (node *trieNode) child(seg string) *trieNode {
+	if idx := strings.IndexByte(seg, ':'); idx >= 0 {
+		prefix := seg[:idx]
+		for _, w := range node.wildcards {
+			if w.prefix == prefix {
+				return w.node
+			}
+		}
+		c := &trieNode{}
+		node.wildcards = append(node.wildcards, wildcardChild{prefix: prefix, node: c})
+		return c
+	}
+	if node.children == nil {
+		node.children = make(map[string]*trieNode)
+	}
+	c, ok := node.children[seg]
+	if !ok {
+		c = &trieNode{}
+		node.children[seg] = c
+	}
+	return c
+}
+
+// matchingWildcards returns node's wildcard children whose prefix text seg
+// starts with, most specific (longest prefix) first, so callers that
+// backtrack on a failed deeper match try the more specific wildcard before a
+// more general one, e.g. "user:id" before a bare ":id" for seg "user42".
+func (node *trieNode) matchingWildcards(seg string) []*trieNode {
+	var matches []wildcardChild
+	for _, w := range node.wildcards {
+		if strings.HasPrefix(seg, w.prefix) {
+			matches = append(matches, w)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return len(matches[i].prefix) > len(matches[j].prefix) })
+	nodes := make([]*trieNode, len(matches))
+	for i, m := range matches {
+		nodes[i] = m.node
+	}
+	return nodes
+}
+
+// insert walks/creates the nodes for pattern's path and records entry under
+// method, in the exact-match or subtree bucket depending on whether pattern
+// is rooted.
+func (t *muxTrie) insert(pattern, method string, entry muxEntry) {
+	host, path := splitHostPattern(pattern)
+	node := t.rootFor(host)
+	segs, trailingSlash := splitSegments(path)
+	for i, seg := range segs {
+		if i == len(segs)-1 && strings.HasSuffix(seg, "...") {
+			if node.catchAll == nil {
+				node.catchAll = &trieNode{}
+				if idx := strings.IndexByte(seg, ':'); idx >= 0 {
+					node.catchAllPrefix = seg[:idx]
+				}
+			}
+			node = node.catchAll
+			trailingSlash = false
+			break
+		}
+		node = node.child(seg)
+	}
+	if trailingSlash {
+		if node.subtree == nil {
+			node.subtree = make(map[string]muxEntry)
+		}
+		node.subtree[method] = entry
+	} else {
+		if node.handlers == nil {
+			node.handlers = make(map[string]muxEntry)
+		}
+		node.handlers[method] = entry
+	}
+}
+
+// findExact walks segs, preferring a literal child over the wildcard child
+// at each step and backtracking to the wildcard when the literal branch
+// doesn't lead anywhere, returning the node reached by consuming all of
+// segs. It returns nil if no such node exists.
+func findExact(node *trieNode, segs []string) *trieNode {
+	if len(segs) == 0 {
+		return node
+	}
+	seg, rest := segs[0], segs[1:]
+	if lit, ok := node.children[seg]; ok {
+		if n := findExact(lit, rest); n != nil {
+			return n
+		}
+	}
+	for _, w := range node.matchingWildcards(seg) {
+		if n := findExact(w, rest); n != nil {
+			return n
+		}
+	}
+	return nil
+}
+
+// findCatchAll returns the deepest catch-all terminal reachable by
+// consuming one or more of segs, preferring to descend into a more specific
+// literal or wildcard branch before falling back to a shallower catch-all.
+// Unlike findSubtree, it's indifferent to a trailing slash on the request:
+// a catch-all captures everything after it, slashes included.
+func findCatchAll(node *trieNode, segs []string) *trieNode {
+	if len(segs) == 0 {
+		return nil
+	}
+	seg, rest := segs[0], segs[1:]
+	if lit, ok := node.children[seg]; ok {
+		if n := findCatchAll(lit, rest); n != nil {
+			return n
+		}
+	}
+	for _, w := range node.matchingWildcards(seg) {
+		if n := findCatchAll(w, rest); n != nil {
+			return n
+		}
+	}
+	if node.catchAll != nil && strings.HasPrefix(seg, node.catchAllPrefix) {
+		return node.catchAll
+	}
+	return nil
+}
+
+// findSubtree returns the deepest node marked as a rooted-subtree reachable
+// by consuming a prefix of segs, preferring to descend as deep as possible
+// before falling back to a shallower ancestor. A subtree match that would
+// consume every segment of segs is only accepted when full is true: a
+// pattern registered with a trailing slash only matches a request reaching
+// that slash directly, not one ending one segment short of it — a request
+// one segment short instead gets the implicit redirect Handle registers
+// alongside it. That redirect only exists for purely literal subtrees,
+// though: one reached through a wildcard segment has no fixed literal URL to
+// redirect to (Handle doesn't register it), so a wildcard branch always
+// treats full as true, and a ":name/" subtree serves a request with or
+// without its trailing slash.
+func findSubtree(node *trieNode, segs []string, full bool) *trieNode {
+	if len(segs) > 0 {
+		seg, rest := segs[0], segs[1:]
+		if lit, ok := node.children[seg]; ok {
+			if n := findSubtree(lit, rest, full); n != nil {
+				return n
+			}
+		}
+		for _, w := range node.matchingWildcards(seg) {
+			if n := findSubtree(w, rest, true); n != nil {
+				return n
+			}
+		}
+		if node.subtree != nil {
+			return node
+		}
+		return nil
+	}
+	if full && node.subtree != nil {
+		return node
+	}
+	return nil
+}
+
+// lookup finds the best entry matching method and path under root, along
+// with whether path matched some registered pattern regardless of method
+// (used to decide between 404 and 405).
+func lookup(root *trieNode, method, path string) (entry muxEntry, found, pathMatched bool) {
+	segs, trailingSlash := splitSegments(path)
+
+	if !trailingSlash {
+		if node := findExact(root, segs); node != nil {
+			if e, ok := node.handlers[method]; ok {
+				return e, true, true
+			}
+			if e, ok := node.handlers[""]; ok {
+				return e, true, true
+			}
+			if len(node.handlers) > 0 {
+				pathMatched = true
+			}
+		}
+	}
+
+	if cat := findCatchAll(root, segs); cat != nil {
+		if e, ok := cat.handlers[method]; ok {
+			return e, true, true
+		}
+		if e, ok := cat.handlers[""]; ok {
+			return e, true, true
+		}
+		if len(cat.handlers) > 0 {
+			pathMatched = true
+		}
+	}
+
+	if sub := findSubtree(root, segs, trailingSlash); sub != nil {
+		if e, ok := sub.subtree[method]; ok {
+			return e, true, true
+		}
+		if e, ok := sub.subtree[""]; ok {
+			return e, true, true
+		}
+		if len(sub.subtree) > 0 {
+			pathMatched = true
+		}
+	}
+
+	return muxEntry{}, false, pathMatched
+}
+
+// allowed collects the sorted, deduplicated set of methods explicitly
+// registered for patterns matching path under root.
+func allowed(root *trieNode, path string) []string {
+	segs, trailingSlash := splitSegments(path)
+	seen := make(map[string]bool)
+
+	collect := func(handlers map[string]muxEntry) {
+		for m := range handlers {
+			if m != "" {
+				seen[m] = true
+			}
+		}
+	}
+
+	if !trailingSlash {
+		if node := findExact(root, segs); node != nil {
+			collect(node.handlers)
+		}
+	}
+	if cat := findCatchAll(root, segs); cat != nil {
+		collect(cat.handlers)
+	}
+	if sub := findSubtree(root, segs, trailingSlash); sub != nil {
+		collect(sub.subtree)
+	}
+
+	if len(seen) == 0 {
+		return nil
+	}
+	methods := make([]string, 0, len(seen))
+	for m := range seen {
+		methods = append(methods, m)
+	}
+	sort.Strings(methods)
+	return methods
+}
@@ -0,0 +1,138 @@
+package pat
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func serve(mux *ServeMux, method, path string) *httptest.ResponseRecorder {
+	r, _ := http.NewRequest(method, path, nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+	return w
+}
+
+func TestTrieLongestWins(t *testing.T) {
+	mux := NewServeMux()
+	mux.HandleFunc("/images/", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("images")) })
+	mux.HandleFunc("/images/thumbnails/", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("thumbnails")) })
+
+	if got := serve(mux, "GET", "/images/thumbnails/foo.png").Body.String(); got != "thumbnails" {
+		t.Errorf("body = %q, want %q", got, "thumbnails")
+	}
+	if got := serve(mux, "GET", "/images/foo.png").Body.String(); got != "images" {
+		t.Errorf("body = %q, want %q", got, "images")
+	}
+}
+
+func TestTrieHostPrecedence(t *testing.T) {
+	mux := NewServeMux()
+	mux.HandleFunc("/codesearch", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("generic")) })
+	mux.HandleFunc("codesearch.google.com/", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("host")) })
+
+	r, _ := http.NewRequest("GET", "/codesearch", nil)
+	r.Host = "codesearch.google.com"
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+	if got := w.Body.String(); got != "host" {
+		t.Errorf("body = %q, want %q", got, "host")
+	}
+
+	r2, _ := http.NewRequest("GET", "/codesearch", nil)
+	r2.Host = "www.google.com"
+	w2 := httptest.NewRecorder()
+	mux.ServeHTTP(w2, r2)
+	if got := w2.Body.String(); got != "generic" {
+		t.Errorf("body = %q, want %q", got, "generic")
+	}
+}
+
+func TestTrieWildcardSubtreeNoTrailingSlash(t *testing.T) {
+	mux := NewServeMux()
+	mux.HandleFunc("/hello/:a/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(Param(r, "a")))
+	})
+
+	// A bare request at the wildcard subtree's own depth is served
+	// directly, not redirected: there's no literal "/hello/:a" URL to
+	// redirect to.
+	w := serve(mux, "GET", "/hello/world")
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", w.Code, http.StatusOK)
+	}
+	if got := w.Body.String(); got != "world" {
+		t.Errorf("body = %q, want %q", got, "world")
+	}
+
+	if got := serve(mux, "GET", "/hello/world/").Body.String(); got != "world" {
+		t.Errorf("body = %q, want %q", got, "world")
+	}
+	if got := serve(mux, "GET", "/hello/world/whatever").Body.String(); got != "world" {
+		t.Errorf("body = %q, want %q", got, "world")
+	}
+}
+
+func TestTrieImplicitRedirect(t *testing.T) {
+	mux := NewServeMux()
+	mux.HandleFunc("/tree/", func(w http.ResponseWriter, r *http.Request) {})
+
+	w := serve(mux, "GET", "/tree")
+	if w.Code != http.StatusMovedPermanently {
+		t.Fatalf("got status %d, want %d", w.Code, http.StatusMovedPermanently)
+	}
+	if loc := w.Header().Get("Location"); loc != "/tree/" {
+		t.Errorf("Location = %q, want %q", loc, "/tree/")
+	}
+}
+
+func TestTrieCatchAll(t *testing.T) {
+	mux := NewServeMux()
+	mux.HandleFunc("/files/:path...", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(Param(r, "path")))
+	})
+
+	if got := serve(mux, "GET", "/files/a/b/c.txt").Body.String(); got != "a/b/c.txt" {
+		t.Errorf("body = %q, want %q", got, "a/b/c.txt")
+	}
+	if got := serve(mux, "GET", "/files/a.txt").Body.String(); got != "a.txt" {
+		t.Errorf("body = %q, want %q", got, "a.txt")
+	}
+	if w := serve(mux, "GET", "/files"); w.Code != http.StatusNotFound {
+		t.Errorf("got status %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestTrieCatchAllRejectsNonFinalSegment(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Handle did not panic for \"...\" in a non-final segment")
+		}
+	}()
+	NewServeMux().HandleFunc("/files/:path.../more", func(w http.ResponseWriter, r *http.Request) {})
+}
+
+func TestTriePrefixedSplat(t *testing.T) {
+	mux := NewServeMux()
+	mux.HandleFunc("/user:id", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(Param(r, "id"))) })
+
+	if got := serve(mux, "GET", "/user42").Body.String(); got != "42" {
+		t.Errorf("body = %q, want %q", got, "42")
+	}
+	if w := serve(mux, "GET", "/admin42"); w.Code != http.StatusNotFound {
+		t.Errorf("got status %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestTrieMultipleWildcardPrefixesAtSameDepth(t *testing.T) {
+	mux := NewServeMux()
+	mux.HandleFunc("/user:a", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("user:" + Param(r, "a"))) })
+	mux.HandleFunc("/admin:b", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("admin:" + Param(r, "b"))) })
+
+	if got := serve(mux, "GET", "/user42").Body.String(); got != "user:42" {
+		t.Errorf("body = %q, want %q", got, "user:42")
+	}
+	if got := serve(mux, "GET", "/admin42").Body.String(); got != "admin:42" {
+		t.Errorf("body = %q, want %q", got, "admin:42")
+	}
+}